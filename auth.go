@@ -0,0 +1,155 @@
+package pop3
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Auth is implemented by SASL authentication mechanisms for use with
+// (*Client).Authenticate.
+type Auth interface {
+	// Name returns the SASL mechanism name, e.g. "PLAIN" or "CRAM-MD5".
+	Name() string
+
+	// Next is called once for each "+" continuation line the server sends,
+	// with that line's base64-decoded challenge (nil if the line carried
+	// none), and returns the response to send back. Authenticate sends no
+	// initial response with the AUTH command itself.
+	Next(challenge []byte) (response []byte, err error)
+}
+
+// Authenticate drives a SASL AUTH exchange (RFC 5034) using the given
+// mechanism: it sends "AUTH <mechanism>", and while the server responds
+// with a "+" continuation line, decodes the challenge, hands it to a.Next,
+// and sends the base64-encoded response back, until the server returns
+// +OK or -ERR.
+func (client *Client) Authenticate(a Auth) error {
+	if client.capabilities != nil && !client.SupportsCapability("SASL") {
+		return errors.New("pop3: server did not advertise the SASL capability")
+	}
+	if err := client.checkBusy(); err != nil {
+		return err
+	}
+
+	client.setDeadline()
+	defer client.resetDeadline()
+
+	line, err := client.Text.SendLine("AUTH %s", a.Name())
+	if err != nil {
+		return err
+	}
+
+	for {
+		challenge, ok, err := client.Text.ReadContinuation(line)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		response, err := a.Next(challenge)
+		if err != nil {
+			return err
+		}
+
+		line, err = client.Text.SendLine("%s", base64.StdEncoding.EncodeToString(response))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !strings.HasPrefix(line, okResponse) {
+		return errors.New(strings.TrimPrefix(line, "-ERR "))
+	}
+	return nil
+}
+
+// Apop authenticates using APOP (RFC 1939): it computes
+// md5(timestamp+secret), where timestamp is the "<...@...>" banner the
+// server included in its greeting, and sends it as "APOP user digest".
+// It returns an error if the greeting did not include a timestamp banner.
+func (client *Client) Apop(user, secret string) error {
+	if err := client.checkBusy(); err != nil {
+		return err
+	}
+	start := strings.IndexByte(client.greeting, '<')
+	end := strings.IndexByte(client.greeting, '>')
+	if start < 0 || end < 0 || end < start {
+		return errors.New("pop3: server greeting did not include an APOP timestamp banner")
+	}
+	timestamp := client.greeting[start : end+1]
+	digest := md5.Sum([]byte(timestamp + secret))
+
+	client.setDeadline()
+	_, err := client.Text.Cmd("APOP %s %x", user, digest)
+	client.resetDeadline()
+	return err
+}
+
+// plainAuth implements the PLAIN SASL mechanism (RFC 4616).
+type plainAuth struct {
+	authzID, user, pass string
+}
+
+// PlainAuth returns an Auth that authenticates as user with pass, using
+// authzID as the authorization identity (leave it empty to authenticate as
+// user itself).
+func PlainAuth(user, pass, authzID string) Auth {
+	return &plainAuth{authzID, user, pass}
+}
+
+func (a *plainAuth) Name() string { return "PLAIN" }
+
+func (a *plainAuth) Next(challenge []byte) ([]byte, error) {
+	return []byte(a.authzID + "\x00" + a.user + "\x00" + a.pass), nil
+}
+
+// loginAuth implements the LOGIN SASL mechanism.
+type loginAuth struct {
+	user, pass string
+	step       int
+}
+
+// LoginAuth returns an Auth that authenticates as user with pass using the
+// LOGIN mechanism.
+func LoginAuth(user, pass string) Auth {
+	return &loginAuth{user: user, pass: pass}
+}
+
+func (a *loginAuth) Name() string { return "LOGIN" }
+
+func (a *loginAuth) Next(challenge []byte) ([]byte, error) {
+	a.step++
+	switch a.step {
+	case 1:
+		return []byte(a.user), nil
+	case 2:
+		return []byte(a.pass), nil
+	default:
+		return nil, errors.New("pop3: unexpected LOGIN challenge")
+	}
+}
+
+// cramMD5Auth implements the CRAM-MD5 SASL mechanism (RFC 2195).
+type cramMD5Auth struct {
+	user, secret string
+}
+
+// CRAMMD5Auth returns an Auth that authenticates as user using an
+// HMAC-MD5 digest of the server's challenge, keyed with secret.
+func CRAMMD5Auth(user, secret string) Auth {
+	return &cramMD5Auth{user, secret}
+}
+
+func (a *cramMD5Auth) Name() string { return "CRAM-MD5" }
+
+func (a *cramMD5Auth) Next(challenge []byte) ([]byte, error) {
+	d := hmac.New(md5.New, []byte(a.secret))
+	d.Write(challenge)
+	return []byte(fmt.Sprintf("%s %x", a.user, d.Sum(nil))), nil
+}
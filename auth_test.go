@@ -0,0 +1,114 @@
+package pop3
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"testing"
+)
+
+// TestAuthenticateCRAMMD5 drives a full SASL AUTH continuation exchange
+// (RFC 5034) against a fake server: AUTH, a base64 challenge, a base64
+// response, and a final +OK.
+func TestAuthenticateCRAMMD5(t *testing.T) {
+	client, server := pipeClient(t)
+	defer server.Close()
+
+	const secret = "s3cr3t"
+	challengeText := "<1896.697170952@dbc.mtview.ca.us>"
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		r := bufio.NewReader(server)
+
+		if line, _ := r.ReadString('\n'); line != "AUTH CRAM-MD5\r\n" {
+			t.Errorf("unexpected command: %q", line)
+		}
+		server.Write([]byte("+ " + base64.StdEncoding.EncodeToString([]byte(challengeText)) + "\r\n"))
+
+		line, _ := r.ReadString('\n')
+		decoded, err := base64.StdEncoding.DecodeString(line[:len(line)-2])
+		if err != nil {
+			t.Errorf("decode response: %v", err)
+		}
+
+		d := hmac.New(md5.New, []byte(secret))
+		d.Write([]byte(challengeText))
+		want := fmt.Sprintf("alice %x", d.Sum(nil))
+		if string(decoded) != want {
+			t.Errorf("unexpected CRAM-MD5 response: got %q want %q", decoded, want)
+		}
+
+		server.Write([]byte("+OK\r\n"))
+	}()
+
+	if err := client.Authenticate(CRAMMD5Auth("alice", secret)); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	<-serverDone
+}
+
+// TestAuthenticateRejected exercises the -ERR path of the AUTH exchange.
+func TestAuthenticateRejected(t *testing.T) {
+	client, server := pipeClient(t)
+	defer server.Close()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		r := bufio.NewReader(server)
+
+		if line, _ := r.ReadString('\n'); line != "AUTH PLAIN\r\n" {
+			t.Errorf("unexpected command: %q", line)
+		}
+		server.Write([]byte("-ERR authentication failed\r\n"))
+	}()
+
+	err := client.Authenticate(PlainAuth("alice", "wrong", ""))
+	if err == nil {
+		t.Fatal("expected Authenticate to fail")
+	}
+
+	<-serverDone
+}
+
+// TestApop exercises the APOP digest computation against the "<...@...>"
+// timestamp banner captured from the greeting.
+func TestApop(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+
+	const timestamp = "<1896.697170952@dbc.mtview.ca.us>"
+	go serverConn.Write([]byte("+OK pop3 ready " + timestamp + "\r\n"))
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		r := bufio.NewReader(serverConn)
+
+		digest := md5.Sum([]byte(timestamp + "tanstaaf"))
+		want := fmt.Sprintf("APOP alice %x\r\n", digest)
+
+		line, _ := r.ReadString('\n')
+		if line != want {
+			t.Errorf("unexpected command: got %q want %q", line, want)
+		}
+		serverConn.Write([]byte("+OK\r\n"))
+	}()
+
+	if err := client.Apop("alice", "tanstaaf"); err != nil {
+		t.Fatalf("Apop: %v", err)
+	}
+
+	<-serverDone
+}
@@ -0,0 +1,179 @@
+package pop3
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"time"
+)
+
+// Option configures a Client created by DialContext.
+type Option func(*dialOptions)
+
+type dialOptions struct {
+	tlsConfig     *tls.Config
+	startTLS      *tls.Config
+	timeout       time.Duration
+	dialer        *net.Dialer
+	lineSeparator string
+	debug         io.Writer
+}
+
+// WithTLS dials the server over TLS from the start, equivalent to DialTLS.
+func WithTLS(config *tls.Config) Option {
+	return func(o *dialOptions) { o.tlsConfig = config }
+}
+
+// WithSTARTTLS dials in plaintext and then issues STLS to upgrade the
+// connection to TLS before the greeting is handed back to the caller.
+func WithSTARTTLS(config *tls.Config) Option {
+	return func(o *dialOptions) { o.startTLS = config }
+}
+
+// WithTimeout sets both the dial timeout and the Client's per-command
+// timeout, as with (*Client).UseTimeouts.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *dialOptions) { o.timeout = timeout }
+}
+
+// WithDialer overrides the *net.Dialer used to establish the connection.
+func WithDialer(dialer *net.Dialer) Option {
+	return func(o *dialOptions) { o.dialer = dialer }
+}
+
+// WithLineSeparator overrides the separator that Retr joins message lines
+// with. It defaults to "\n".
+func WithLineSeparator(sep string) Option {
+	return func(o *dialOptions) { o.lineSeparator = sep }
+}
+
+// WithDebug tees all protocol traffic, commands and responses alike, to w.
+func WithDebug(w io.Writer) Option {
+	return func(o *dialOptions) { o.debug = w }
+}
+
+// DialContext connects to addr and returns a Client configured by opts. ctx
+// governs the dial, reading the greeting, and the optional STARTTLS
+// handshake; use (*Client).CmdContext afterwards for per-command
+// cancellation.
+func DialContext(ctx context.Context, addr string, opts ...Option) (*Client, error) {
+	o := dialOptions{dialer: &net.Dialer{}, lineSeparator: defaultLineSeparator}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.timeout > 0 {
+		o.dialer.Timeout = o.timeout
+	}
+
+	host, _, _ := net.SplitHostPort(addr)
+
+	var conn net.Conn
+	var err error
+	if o.tlsConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: o.dialer, Config: setServerName(o.tlsConfig, host)}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = o.dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{conn: conn, lineSeparator: o.lineSeparator, debug: o.debug}
+	client.Text = NewConnection(client.wrapConn(conn))
+	if o.timeout > 0 {
+		client.UseTimeouts(o.timeout)
+	}
+
+	cancel := client.withContext(ctx)
+	client.setDeadline()
+	greeting, err := client.Text.ReadResponse()
+	client.resetDeadline()
+	cancel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	client.greetingRead = true
+	client.greeting = greeting
+
+	if o.startTLS != nil {
+		if err := client.startTLS(ctx, o.startTLS); err != nil {
+			client.Text.Close()
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+// debugConn tees protocol traffic through w, for use with WithDebug.
+type debugConn struct {
+	io.ReadWriteCloser
+	w io.Writer
+}
+
+func (d *debugConn) Read(p []byte) (int, error) {
+	n, err := d.ReadWriteCloser.Read(p)
+	if n > 0 {
+		d.w.Write(p[:n])
+	}
+	return n, err
+}
+
+func (d *debugConn) Write(p []byte) (int, error) {
+	n, err := d.ReadWriteCloser.Write(p)
+	if n > 0 {
+		d.w.Write(p[:n])
+	}
+	return n, err
+}
+
+// withContext forces client's connection deadline to expire if ctx is done
+// before the returned cancel func is called, and clears that forced
+// deadline again once cancel runs. It is a no-op for a context with no
+// Done channel, such as context.Background().
+func (client *Client) withContext(ctx context.Context) (cancel func()) {
+	if ctx == nil || ctx.Done() == nil {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			client.conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+		if ctx.Err() != nil {
+			// The connection's deadline was forced into the past to abort
+			// the in-flight command; clear it regardless of whether a
+			// blanket client.timeout is configured, or every later command
+			// would fail instantly with a stale deadline.
+			client.conn.SetDeadline(time.Time{})
+		}
+	}
+}
+
+// CmdContext behaves like client.Text.Cmd, but aborts the command by
+// forcing the connection's deadline if ctx is done before the server
+// responds.
+func (client *Client) CmdContext(ctx context.Context, format string, args ...interface{}) (string, error) {
+	if err := client.checkBusy(); err != nil {
+		return "", err
+	}
+
+	cancel := client.withContext(ctx)
+	defer cancel()
+
+	client.setDeadline()
+	defer client.resetDeadline()
+	return client.Text.Cmd(format, args...)
+}
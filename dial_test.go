@@ -0,0 +1,81 @@
+package pop3
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestCmdContextCancelDoesNotWedgeConnection reproduces a regression where a
+// CmdContext call cancelled by its context left the connection's deadline
+// forced into the past, so every subsequent command (even a plain
+// client.Text.Cmd with no context at all) failed instantly instead of
+// talking to the server.
+func TestCmdContextCancelDoesNotWedgeConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("+OK pop3 ready\r\n"))
+
+		r := bufio.NewReader(conn)
+		// First NOOP: deliberately left unanswered so the context deadline
+		// below fires while the client is waiting on it.
+		if _, err := r.ReadString('\n'); err != nil {
+			return
+		}
+
+		// Second NOOP: answered normally, once the client retries with a
+		// plain client.Text.Cmd that has no context at all.
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if line == "NOOP\r\n" {
+			conn.Write([]byte("+OK\r\n"))
+		}
+	}()
+
+	client, err := Dial(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Text.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.CmdContext(ctx, "NOOP"); err == nil {
+		t.Fatal("expected CmdContext to fail once its context expired")
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.Text.Cmd("NOOP")
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("NOOP after cancelled CmdContext: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("NOOP after cancelled CmdContext never completed")
+	}
+
+	<-serverDone
+}
@@ -1,8 +1,10 @@
 package pop3
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
+	"io"
 	"net"
 	"strconv"
 	"strings"
@@ -13,6 +15,49 @@ type Client struct {
 	Text    *Connection
 	conn    net.Conn
 	timeout time.Duration
+
+	// greetingRead is set once the server's initial greeting has been
+	// consumed, which every exported constructor does before returning a
+	// usable Client.
+	greetingRead bool
+
+	// capabilities caches the result of the last Capa call, or nil if Capa
+	// has not been called yet.
+	capabilities map[string][]string
+
+	// greeting is the text of the server's banner, captured for Apop.
+	greeting string
+
+	// busy is set while a RetrReader or Top reader is open. Other commands
+	// refuse to run until it is read to completion or closed, since they'd
+	// otherwise desynchronize the connection from the multi-line response
+	// still being streamed.
+	busy bool
+
+	// lineSeparator is what Retr joins message lines with. It defaults to
+	// defaultLineSeparator and can be overridden with WithLineSeparator.
+	lineSeparator string
+
+	// debug, if set via WithDebug, is re-applied by wrapConn whenever the
+	// underlying connection is rewrapped, such as after StartTLS.
+	debug io.Writer
+}
+
+// wrapConn tees rw through client.debug, if the Client was dialed with
+// WithDebug, and returns rw unchanged otherwise.
+func (client *Client) wrapConn(rw io.ReadWriteCloser) io.ReadWriteCloser {
+	if client.debug == nil {
+		return rw
+	}
+	return &debugConn{ReadWriteCloser: rw, w: client.debug}
+}
+
+// checkBusy returns an error if a RetrReader or Top reader is still open.
+func (client *Client) checkBusy() error {
+	if client.busy {
+		return errors.New("pop3: a RETR or TOP reader is still open")
+	}
+	return nil
 }
 
 // MessageInfo represents the message attributes returned by a LIST command.
@@ -22,54 +67,147 @@ type MessageInfo struct {
 	Uid  string // Message UID
 }
 
-var lineSeparator = "\n"
+var defaultLineSeparator = "\n"
 
+// Dial connects to addr over plain TCP. See DialContext for functional
+// options (TLS, STARTTLS, timeouts, debug tracing, ...) and context support.
 func Dial(addr string) (*Client, error) {
-	conn, err := net.Dial("tcp", addr)
-	if err != nil {
-		return nil, err
-	}
-	return NewClient(conn)
+	return DialContext(context.Background(), addr)
 }
 
+// DialTimeout connects to addr over plain TCP, aborting the dial after
+// timeout and installing it as the Client's per-command timeout too.
 func DialTimeout(addr string, timeout time.Duration) (*Client, error) {
-	conn, err := net.DialTimeout("tcp", addr, timeout)
-	if err != nil {
-		return nil, err
-	}
-	return NewClient(conn)
+	return DialContext(context.Background(), addr, WithTimeout(timeout))
 }
 
+// DialTLS connects to addr over TLS.
 func DialTLS(addr string, config *tls.Config) (*Client, error) {
-	host, _, _ := net.SplitHostPort(addr)
-	conn, err := tls.Dial("tcp", addr, setServerName(config, host))
+	return DialContext(context.Background(), addr, WithTLS(config))
+}
+
+// DialTLSTimeout connects to addr over TLS, aborting the dial after timeout
+// and installing it as the Client's per-command timeout too.
+func DialTLSTimeout(addr string, config *tls.Config, timeout time.Duration) (*Client, error) {
+	return DialContext(context.Background(), addr, WithTLS(config), WithTimeout(timeout))
+}
+
+func NewClient(conn net.Conn) (*Client, error) {
+	client := &Client{Text: NewConnection(conn), conn: conn, lineSeparator: defaultLineSeparator}
+	// read greeting
+	client.setDeadline()
+	greeting, err := client.Text.ReadResponse()
+	client.resetDeadline()
 	if err != nil {
 		return nil, err
 	}
-	return NewClient(conn)
+	client.greetingRead = true
+	client.greeting = greeting
+	return client, nil
 }
 
-func DialTLSTimeout(addr string, config *tls.Config, timeout time.Duration) (*Client, error) {
-	host, _, _ := net.SplitHostPort(addr)
-	d := net.Dialer{Timeout: timeout}
-	conn, err := tls.DialWithDialer(&d, "tcp", addr, setServerName(config, host))
+// StartTLS issues the POP3 STLS command (RFC 2595) to upgrade an
+// unencrypted connection to TLS. On success, the underlying connection and
+// client.Text are replaced with ones wrapping the new *tls.Conn, and the
+// rest of the session (USER/PASS, APOP, message retrieval, ...) proceeds
+// as normal over the encrypted channel.
+//
+// StartTLS fails if the greeting hasn't been read yet or if the connection
+// is already using TLS.
+func (client *Client) StartTLS(config *tls.Config) error {
+	return client.startTLS(context.Background(), config)
+}
+
+// startTLS is the shared implementation behind the public StartTLS and
+// DialContext's WithSTARTTLS upgrade. ctx (and any blanket client.timeout)
+// bounds the whole STLS command plus TLS handshake, not just the command,
+// since a peer that acknowledges STLS but stalls the handshake would
+// otherwise hang past both.
+func (client *Client) startTLS(ctx context.Context, config *tls.Config) error {
+	if !client.greetingRead {
+		return errors.New("pop3: StartTLS called before the greeting was read")
+	}
+	if _, ok := client.conn.(*tls.Conn); ok {
+		return errors.New("pop3: connection is already using TLS")
+	}
+	if client.capabilities != nil && !client.SupportsCapability("STLS") {
+		return errors.New("pop3: server did not advertise the STLS capability")
+	}
+	if err := client.checkBusy(); err != nil {
+		return err
+	}
+
+	cancel := client.withContext(ctx)
+	defer cancel()
+
+	client.setDeadline()
+	defer client.resetDeadline()
+
+	_, err := client.Text.Cmd("STLS")
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return NewClient(conn)
+
+	host, _, _ := net.SplitHostPort(client.conn.RemoteAddr().String())
+	tlsConn := tls.Client(client.conn, setServerName(config, host))
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+
+	client.conn = tlsConn
+	client.Text = NewConnection(client.wrapConn(tlsConn))
+
+	if client.capabilities != nil {
+		client.Capa()
+	}
+	return nil
 }
 
-func NewClient(conn net.Conn) (*Client, error) {
-	text := NewConnection(conn)
-	client := &Client{Text: text, conn: conn}
-	// read greeting
+// Capa sends the CAPA command (RFC 2449) and returns the server's
+// advertised capabilities as a map from capability name to the arguments
+// that followed it on the same line, e.g. {"SASL": {"PLAIN", "CRAM-MD5"}}.
+// The result is cached on the client for use by SupportsCapability and
+// SASLMechanisms.
+func (client *Client) Capa() (map[string][]string, error) {
+	if err := client.checkBusy(); err != nil {
+		return nil, err
+	}
 	client.setDeadline()
-	_, err := client.Text.ReadResponse()
+	_, err := client.Text.Cmd("CAPA")
+	if err != nil {
+		client.resetDeadline()
+		return nil, err
+	}
+	lines, err := client.Text.ReadMultiLines()
 	client.resetDeadline()
 	if err != nil {
 		return nil, err
 	}
-	return client, nil
+
+	capabilities := make(map[string][]string, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		capabilities[strings.ToUpper(fields[0])] = fields[1:]
+	}
+	client.capabilities = capabilities
+	return capabilities, nil
+}
+
+// SupportsCapability reports whether the server advertised name in the
+// last call to Capa. It returns false if Capa has not been called yet.
+func (client *Client) SupportsCapability(name string) bool {
+	_, ok := client.capabilities[strings.ToUpper(name)]
+	return ok
+}
+
+// SASLMechanisms returns the SASL mechanisms advertised via the SASL
+// capability in the last call to Capa, or nil if Capa has not been called
+// or the server did not advertise SASL.
+func (client *Client) SASLMechanisms() []string {
+	return client.capabilities[strings.ToUpper("SASL")]
 }
 
 func (client *Client) UseTimeouts(timeout time.Duration) {
@@ -77,6 +215,9 @@ func (client *Client) UseTimeouts(timeout time.Duration) {
 }
 
 func (client *Client) User(user string) (err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	_, err = client.Text.Cmd("USER %s", user)
 	client.resetDeadline()
@@ -87,6 +228,9 @@ func (client *Client) User(user string) (err error) {
 // unencrypted unless the connection is already secured by TLS (via DialTLS or
 // some other mechanism).
 func (client *Client) Pass(password string) (err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	_, err = client.Text.Cmd("PASS %s", password)
 	client.resetDeadline()
@@ -95,6 +239,9 @@ func (client *Client) Pass(password string) (err error) {
 
 // Auth sends the given username and password to the server.
 func (client *Client) Auth(username, password string) (err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	err = client.User(username)
 	if err != nil {
 		return
@@ -109,6 +256,9 @@ func (client *Client) Auth(username, password string) (err error) {
 // maildrop is ignored. In the event of an error, all returned numeric values
 // will be 0.
 func (client *Client) Stat() (count, size uint32, err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	l, err := client.Text.Cmd("STAT")
 	if err != nil {
@@ -131,6 +281,9 @@ func (client *Client) Stat() (count, size uint32, err error) {
 // if it exists. If the message does not exist, or another error is encountered,
 // the returned size will be 0.
 func (client *Client) List(msgSeqNum uint32) (size uint32, err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	l, err := client.Text.Cmd("LIST %d", msgSeqNum)
 	if err != nil {
@@ -147,6 +300,9 @@ func (client *Client) List(msgSeqNum uint32) (size uint32, err error) {
 // ListAll returns a list of MessageInfo for all messages, containing their
 // sequence number and size.
 func (client *Client) ListAll() (msgInfos []*MessageInfo, err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	_, err = client.Text.Cmd("LIST")
 	if err != nil {
@@ -180,19 +336,25 @@ func (client *Client) ListAll() (msgInfos []*MessageInfo, err error) {
 // Retr downloads and returns the given message. The lines are separated by LF,
 // whatever the server sent.
 func (client *Client) Retr(msg uint32) (text string, err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	_, err = client.Text.Cmd("RETR %d", msg)
 	if err != nil {
 		return "", err
 	}
 	lines, err := client.Text.ReadMultiLines()
-	text = strings.Join(lines, lineSeparator)
+	text = strings.Join(lines, client.lineSeparator)
 	client.resetDeadline()
 	return
 }
 
 // Dele marks the given message as deleted.
 func (client *Client) Dele(msg uint32) (err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	_, err = client.Text.Cmd("DELE %d", msg)
 	client.resetDeadline()
@@ -202,6 +364,9 @@ func (client *Client) Dele(msg uint32) (err error) {
 // Noop does nothing, but will prolong the end of the connection if the server
 // has a timeout set.
 func (client *Client) Noop() (err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	_, err = client.Text.Cmd("NOOP")
 	client.resetDeadline()
@@ -210,6 +375,9 @@ func (client *Client) Noop() (err error) {
 
 // Rset unmarks any messages marked for deletion previously in this session.
 func (client *Client) Rset() (err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	_, err = client.Text.Cmd("RSET")
 	client.resetDeadline()
@@ -218,6 +386,9 @@ func (client *Client) Rset() (err error) {
 
 // Quit sends the QUIT message to the POP3 server and closes the connection.
 func (client *Client) Quit() (err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	_, err = client.Text.Cmd("QUIT")
 	if err != nil {
@@ -229,6 +400,9 @@ func (client *Client) Quit() (err error) {
 
 // Uidl retrieves the unique ID of the message referenced by the sequence number.
 func (client *Client) Uidl(msgSeqNum uint32) (uid string, err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	line, err := client.Text.Cmd("UIDL %d", msgSeqNum)
 	if err != nil {
@@ -241,6 +415,9 @@ func (client *Client) Uidl(msgSeqNum uint32) (uid string, err error) {
 
 // Uidl retrieves the unique ID of the message referenced by the sequence number.
 func (client *Client) UidlAll() (msgInfos []*MessageInfo, err error) {
+	if err = client.checkBusy(); err != nil {
+		return
+	}
 	client.setDeadline()
 	_, err = client.Text.Cmd("UIDL")
 	if err != nil {
@@ -0,0 +1,132 @@
+package pop3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+)
+
+// ReconnectingClient wraps a Client and transparently redials, re-
+// authenticates, and rebuilds its UID-to-sequence-number mapping whenever a
+// command fails with what looks like a broken connection. POP3 sequence
+// numbers are only meaningful for the lifetime of a single session, so
+// addressing messages by UID is what makes a reconnect transparent to the
+// caller. A ReconnectingClient is not safe for concurrent use.
+type ReconnectingClient struct {
+	addr string
+	opts []Option
+	auth func(*Client) error
+
+	client   *Client
+	uidToSeq map[string]uint32
+}
+
+// NewReconnectingClient dials addr, authenticates by calling auth, and
+// returns a ReconnectingClient ready to serve UID-addressed commands. auth
+// is called again on every subsequent reconnect.
+func NewReconnectingClient(ctx context.Context, addr string, auth func(*Client) error, opts ...Option) (*ReconnectingClient, error) {
+	rc := &ReconnectingClient{addr: addr, opts: opts, auth: auth}
+	if err := rc.reconnect(ctx); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+func (rc *ReconnectingClient) reconnect(ctx context.Context) error {
+	client, err := DialContext(ctx, rc.addr, rc.opts...)
+	if err != nil {
+		return err
+	}
+	if err := rc.auth(client); err != nil {
+		client.Text.Close()
+		return err
+	}
+
+	infos, err := client.UidlAll()
+	if err != nil {
+		client.Text.Close()
+		return err
+	}
+
+	uidToSeq := make(map[string]uint32, len(infos))
+	for _, info := range infos {
+		uidToSeq[info.Uid] = info.Seq
+	}
+
+	if rc.client != nil {
+		rc.client.Text.Close()
+	}
+	rc.client = client
+	rc.uidToSeq = uidToSeq
+	return nil
+}
+
+// isReconnectable reports whether err looks like a broken connection,
+// rather than a protocol-level failure (-ERR) that a reconnect wouldn't fix.
+func isReconnectable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.ErrClosedPipe) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// withReconnect runs fn against the current client, and on a connection
+// error redials, re-authenticates, rebuilds the UID map, and retries fn
+// once more.
+func (rc *ReconnectingClient) withReconnect(ctx context.Context, fn func(*Client) error) error {
+	err := fn(rc.client)
+	if !isReconnectable(err) {
+		return err
+	}
+	if err := rc.reconnect(ctx); err != nil {
+		return err
+	}
+	return fn(rc.client)
+}
+
+// RetrByUID downloads the message with the given UID, transparently
+// reconnecting and rebuilding the UID map if the session was lost.
+func (rc *ReconnectingClient) RetrByUID(uid string) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+	err := rc.withReconnect(context.Background(), func(client *Client) error {
+		seq, ok := rc.uidToSeq[uid]
+		if !ok {
+			return errors.New("pop3: unknown message UID")
+		}
+		r, err := client.RetrReader(seq)
+		if err != nil {
+			return err
+		}
+		reader = r
+		return nil
+	})
+	return reader, err
+}
+
+// DeleByUID marks the message with the given UID as deleted, transparently
+// reconnecting and rebuilding the UID map if the session was lost.
+func (rc *ReconnectingClient) DeleByUID(uid string) error {
+	return rc.withReconnect(context.Background(), func(client *Client) error {
+		seq, ok := rc.uidToSeq[uid]
+		if !ok {
+			return errors.New("pop3: unknown message UID")
+		}
+		return client.Dele(seq)
+	})
+}
+
+// Close sends QUIT, which closes the underlying connection on success; if
+// QUIT itself fails, Close closes the connection directly instead of
+// leaving it dangling.
+func (rc *ReconnectingClient) Close() error {
+	err := rc.client.Quit()
+	if err != nil {
+		rc.client.Text.Close()
+	}
+	return err
+}
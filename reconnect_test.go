@@ -0,0 +1,156 @@
+package pop3
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+)
+
+// TestReconnectingClientSurvivesBrokenConnection kills the first connection
+// mid-session (after the UID map has been built, while a RETR is in
+// flight) and verifies that RetrByUID transparently reconnects,
+// re-authenticates, rebuilds the UID-to-sequence map against the new
+// session's (different) sequence numbers, and still retrieves the right
+// message by UID.
+func TestReconnectingClientSurvivesBrokenConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+
+		// First session: authenticates, reports the message at sequence 1,
+		// then the connection is dropped as soon as RETR comes in.
+		conn1, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		r1 := bufio.NewReader(conn1)
+		conn1.Write([]byte("+OK pop3 ready\r\n"))
+		if line, _ := r1.ReadString('\n'); line != "USER alice\r\n" {
+			t.Errorf("session 1: unexpected command: %q", line)
+		}
+		conn1.Write([]byte("+OK\r\n"))
+		if line, _ := r1.ReadString('\n'); line != "PASS secret\r\n" {
+			t.Errorf("session 1: unexpected command: %q", line)
+		}
+		conn1.Write([]byte("+OK\r\n"))
+		if line, _ := r1.ReadString('\n'); line != "UIDL\r\n" {
+			t.Errorf("session 1: unexpected command: %q", line)
+		}
+		conn1.Write([]byte("+OK\r\n1 abc\r\n.\r\n"))
+		if line, _ := r1.ReadString('\n'); line != "RETR 1\r\n" {
+			t.Errorf("session 1: unexpected command: %q", line)
+		}
+		conn1.Close()
+
+		// Second session: the message "abc" now has a different sequence
+		// number, proving the reconnect rebuilt the UID map rather than
+		// reusing stale sequence numbers.
+		conn2, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn2.Close()
+		r2 := bufio.NewReader(conn2)
+		conn2.Write([]byte("+OK pop3 ready\r\n"))
+		if line, _ := r2.ReadString('\n'); line != "USER alice\r\n" {
+			t.Errorf("session 2: unexpected command: %q", line)
+		}
+		conn2.Write([]byte("+OK\r\n"))
+		if line, _ := r2.ReadString('\n'); line != "PASS secret\r\n" {
+			t.Errorf("session 2: unexpected command: %q", line)
+		}
+		conn2.Write([]byte("+OK\r\n"))
+		if line, _ := r2.ReadString('\n'); line != "UIDL\r\n" {
+			t.Errorf("session 2: unexpected command: %q", line)
+		}
+		conn2.Write([]byte("+OK\r\n2 abc\r\n.\r\n"))
+		if line, _ := r2.ReadString('\n'); line != "RETR 2\r\n" {
+			t.Errorf("session 2: unexpected command: %q", line)
+		}
+		conn2.Write([]byte("+OK\r\nhello\r\n.\r\n"))
+
+		if line, _ := r2.ReadString('\n'); line != "QUIT\r\n" {
+			t.Errorf("session 2: unexpected command: %q", line)
+		}
+		conn2.Write([]byte("+OK bye\r\n"))
+	}()
+
+	auth := func(client *Client) error {
+		return client.Auth("alice", "secret")
+	}
+
+	rc, err := NewReconnectingClient(context.Background(), ln.Addr().String(), auth)
+	if err != nil {
+		t.Fatalf("NewReconnectingClient: %v", err)
+	}
+
+	reader, err := rc.RetrByUID("abc")
+	if err != nil {
+		t.Fatalf("RetrByUID: %v", err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	reader.Close()
+	if string(body) != "hello\r\n" {
+		t.Fatalf("unexpected message body: %q", body)
+	}
+
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	<-serverDone
+}
+
+// TestReconnectingClientCloseDoesNotDoubleClose verifies that Close doesn't
+// attempt to close the underlying connection a second time after a
+// successful QUIT already closed it (QUIT closes client.Text itself on
+// success; Close used to unconditionally close it again).
+func TestReconnectingClientCloseDoesNotDoubleClose(t *testing.T) {
+	client, server := pipeClient(t)
+	defer server.Close()
+
+	counter := &closeCounter{ReadWriteCloser: client.Text.conn}
+	client.Text = NewConnection(counter)
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		r := bufio.NewReader(server)
+		if line, _ := r.ReadString('\n'); line != "QUIT\r\n" {
+			t.Errorf("unexpected command: %q", line)
+		}
+		server.Write([]byte("+OK bye\r\n"))
+	}()
+
+	rc := &ReconnectingClient{client: client}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if counter.closes != 1 {
+		t.Fatalf("underlying connection closed %d times, want 1", counter.closes)
+	}
+
+	<-serverDone
+}
+
+// closeCounter counts how many times the wrapped connection is closed.
+type closeCounter struct {
+	io.ReadWriteCloser
+	closes int
+}
+
+func (c *closeCounter) Close() error {
+	c.closes++
+	return c.ReadWriteCloser.Close()
+}
@@ -0,0 +1,126 @@
+package pop3
+
+import (
+	"errors"
+	"io"
+	"net/mail"
+)
+
+// messageReader streams a multi-line POP3 response (as returned by RETR or
+// TOP) without buffering the whole message in memory. It strips the
+// leading-dot byte-stuffing from the wire as it reads, and stops at the
+// terminating ".CRLF" line.
+type messageReader struct {
+	client *Client
+	buf    []byte
+	eof    bool
+	closed bool
+}
+
+func (r *messageReader) fillLine() error {
+	line, _, err := r.client.Text.Reader.ReadLine()
+	if err != nil {
+		r.eof = true
+		return err
+	}
+	if string(line) == endResponse {
+		r.eof = true
+		return nil
+	}
+	if len(line) > 0 && line[0] == '.' {
+		line = line[1:]
+	}
+	r.buf = append(r.buf, line...)
+	r.buf = append(r.buf, '\r', '\n')
+	return nil
+}
+
+func (r *messageReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, errors.New("pop3: read from a closed message reader")
+	}
+	for len(r.buf) == 0 && !r.eof {
+		if err := r.fillLine(); err != nil {
+			return 0, err
+		}
+	}
+	if len(r.buf) == 0 {
+		r.finalize()
+		return 0, io.EOF
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close drains any unread bytes so the client stays in sync for the next
+// command. It is safe to call Close without having read the reader to
+// completion, and to call it more than once.
+func (r *messageReader) Close() error {
+	for !r.eof {
+		r.buf = r.buf[:0]
+		if err := r.fillLine(); err != nil {
+			break
+		}
+	}
+	r.finalize()
+	return nil
+}
+
+func (r *messageReader) finalize() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	r.client.busy = false
+	r.client.resetDeadline()
+}
+
+// RetrReader downloads the given message and returns a reader over its
+// contents, without buffering the whole message in memory. Only one
+// RetrReader or Top reader may be open at a time; other commands return an
+// error until it is read to completion or closed.
+func (client *Client) RetrReader(msg uint32) (io.ReadCloser, error) {
+	if err := client.checkBusy(); err != nil {
+		return nil, err
+	}
+	client.setDeadline()
+	_, err := client.Text.Cmd("RETR %d", msg)
+	if err != nil {
+		client.resetDeadline()
+		return nil, err
+	}
+	client.busy = true
+	return &messageReader{client: client}, nil
+}
+
+// Top downloads the headers and the first nLines lines of the body of the
+// given message, and returns a reader over the result. See RetrReader for
+// the streaming and concurrency semantics.
+func (client *Client) Top(msg uint32, nLines uint32) (io.ReadCloser, error) {
+	if client.capabilities != nil && !client.SupportsCapability("TOP") {
+		return nil, errors.New("pop3: server did not advertise the TOP capability")
+	}
+	if err := client.checkBusy(); err != nil {
+		return nil, err
+	}
+	client.setDeadline()
+	_, err := client.Text.Cmd("TOP %d %d", msg, nLines)
+	if err != nil {
+		client.resetDeadline()
+		return nil, err
+	}
+	client.busy = true
+	return &messageReader{client: client}, nil
+}
+
+// RetrMessage downloads the given message and parses it with
+// mail.ReadMessage, giving callers parsed headers and a Body reader without
+// buffering the whole message in memory.
+func (client *Client) RetrMessage(msg uint32) (*mail.Message, error) {
+	r, err := client.RetrReader(msg)
+	if err != nil {
+		return nil, err
+	}
+	return mail.ReadMessage(r)
+}
@@ -0,0 +1,104 @@
+package pop3
+
+import (
+	"bufio"
+	"net"
+	"testing"
+)
+
+// pipeClient returns a *Client wired to one end of an in-memory net.Pipe,
+// along with the server's end for the test to drive by hand.
+func pipeClient(t *testing.T) (*Client, net.Conn) {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	go serverConn.Write([]byte("+OK pop3 ready\r\n"))
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client, serverConn
+}
+
+func TestRetrReaderBusyUntilClosed(t *testing.T) {
+	client, server := pipeClient(t)
+	defer server.Close()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		r := bufio.NewReader(server)
+
+		if line, _ := r.ReadString('\n'); line != "RETR 1\r\n" {
+			t.Errorf("unexpected command: %q", line)
+		}
+		server.Write([]byte("+OK\r\n"))
+		server.Write([]byte("line one\r\nline two\r\n.\r\n"))
+
+		if line, _ := r.ReadString('\n'); line != "NOOP\r\n" {
+			t.Errorf("unexpected command: %q", line)
+		}
+		server.Write([]byte("+OK\r\n"))
+	}()
+
+	reader, err := client.RetrReader(1)
+	if err != nil {
+		t.Fatalf("RetrReader: %v", err)
+	}
+
+	if err := client.Noop(); err == nil {
+		t.Fatal("expected Noop to fail while a RetrReader is open")
+	}
+
+	if err := reader.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if err := client.Noop(); err != nil {
+		t.Fatalf("Noop after Close: %v", err)
+	}
+
+	<-serverDone
+}
+
+func TestTopReaderBusyUntilClosed(t *testing.T) {
+	client, server := pipeClient(t)
+	defer server.Close()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		r := bufio.NewReader(server)
+
+		if line, _ := r.ReadString('\n'); line != "TOP 1 5\r\n" {
+			t.Errorf("unexpected command: %q", line)
+		}
+		server.Write([]byte("+OK\r\n"))
+		server.Write([]byte("header: value\r\n\r\nbody\r\n.\r\n"))
+
+		if line, _ := r.ReadString('\n'); line != "NOOP\r\n" {
+			t.Errorf("unexpected command: %q", line)
+		}
+		server.Write([]byte("+OK\r\n"))
+	}()
+
+	reader, err := client.Top(1, 5)
+	if err != nil {
+		t.Fatalf("Top: %v", err)
+	}
+
+	if err := client.Noop(); err == nil {
+		t.Fatal("expected Noop to fail while a Top reader is open")
+	}
+
+	if err := reader.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if err := client.Noop(); err != nil {
+		t.Fatalf("Noop after Close: %v", err)
+	}
+
+	<-serverDone
+}
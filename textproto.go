@@ -2,9 +2,11 @@ package pop3
 
 import (
 	"bufio"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 type Connection struct {
@@ -63,6 +65,34 @@ func (c *Connection) ReadResponse() (result string, err error) {
 	return
 }
 
+// SendLine writes a formatted command followed by CRLF and returns the raw
+// response line, without interpreting it as +OK/-ERR. It is used by
+// exchanges that need to see "+" continuation lines, such as SASL AUTH.
+func (c *Connection) SendLine(format string, args ...interface{}) (line string, err error) {
+	c.SendCMD(format, args...)
+	response, _, err := c.Reader.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	return string(response), nil
+}
+
+// ReadContinuation reports whether line is a SASL "+" continuation
+// response (RFC 5034) and, if so, base64-decodes the challenge that
+// followed it. ok is false for a final "+OK"/"-ERR" line.
+func (c *Connection) ReadContinuation(line string) (challenge []byte, ok bool, err error) {
+	if !strings.HasPrefix(line, "+") || strings.HasPrefix(line, okResponse) {
+		return nil, false, nil
+	}
+
+	data := strings.TrimSpace(strings.TrimPrefix(line, "+"))
+	if data == "" {
+		return nil, true, nil
+	}
+	challenge, err = base64.StdEncoding.DecodeString(data)
+	return challenge, true, err
+}
+
 func (c *Connection) ReadMultiLines() (lines []string, err error) {
 	lines = make([]string, 0)
 	var bytes []byte
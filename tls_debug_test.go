@@ -0,0 +1,118 @@
+package pop3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// generateTestCert creates an ephemeral, self-signed certificate for
+// 127.0.0.1, for use by tests that need a real TLS handshake to complete.
+func generateTestCert(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("X509KeyPair: %v", err)
+	}
+	return cert
+}
+
+// TestDialContextSTARTTLSKeepsDebugTracing reproduces a regression where
+// WithDebug tracing silently stopped after a successful STARTTLS upgrade,
+// because StartTLS rewrapped the raw *tls.Conn instead of the
+// debug-teeing stream DialContext originally built.
+func TestDialContextSTARTTLSKeepsDebugTracing(t *testing.T) {
+	cert := generateTestCert(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("+OK pop3 ready\r\n"))
+		r := bufio.NewReader(conn)
+		if _, err := r.ReadString('\n'); err != nil { // STLS
+			return
+		}
+		conn.Write([]byte("+OK\r\n"))
+
+		tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+		if err := tlsConn.Handshake(); err != nil {
+			t.Errorf("server handshake: %v", err)
+			return
+		}
+
+		tr := bufio.NewReader(tlsConn)
+		if line, _ := tr.ReadString('\n'); line != "NOOP\r\n" {
+			t.Errorf("unexpected post-STLS command: %q", line)
+		}
+		tlsConn.Write([]byte("+OK\r\n"))
+	}()
+
+	var debugBuf bytes.Buffer
+	client, err := DialContext(context.Background(), ln.Addr().String(),
+		WithSTARTTLS(&tls.Config{InsecureSkipVerify: true}),
+		WithDebug(&debugBuf),
+	)
+	if err != nil {
+		t.Fatalf("DialContext: %v", err)
+	}
+	defer client.Text.Close()
+
+	preSTLS := debugBuf.Len()
+	if preSTLS == 0 {
+		t.Fatal("expected debug tracing to have captured the greeting and STLS exchange")
+	}
+
+	if err := client.Noop(); err != nil {
+		t.Fatalf("Noop after STARTTLS: %v", err)
+	}
+
+	if debugBuf.Len() <= preSTLS {
+		t.Fatalf("expected debug tracing to keep growing after STARTTLS: before=%d after=%d", preSTLS, debugBuf.Len())
+	}
+
+	<-serverDone
+}
@@ -0,0 +1,54 @@
+package pop3
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestDialContextSTARTTLSHonorsCtx reproduces a regression where
+// DialContext's WithSTARTTLS handshake ignored ctx entirely: a server that
+// acknowledged STLS but never completed the TLS handshake used to hang
+// DialContext well past the context deadline.
+func TestDialContextSTARTTLSHonorsCtx(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("+OK pop3 ready\r\n"))
+		r := bufio.NewReader(conn)
+		if _, err := r.ReadString('\n'); err != nil { // STLS
+			return
+		}
+		conn.Write([]byte("+OK\r\n"))
+		// Deliberately never send a TLS ServerHello, so the client's
+		// handshake blocks until ctx (or the deadline it installs) fires.
+		time.Sleep(2 * time.Second)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = DialContext(ctx, ln.Addr().String(), WithSTARTTLS(&tls.Config{InsecureSkipVerify: true}))
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("expected DialContext to fail when the STARTTLS handshake never completes")
+	}
+	if elapsed > time.Second {
+		t.Fatalf("DialContext took %v to honor a 200ms ctx deadline during the STARTTLS handshake", elapsed)
+	}
+}